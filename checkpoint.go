@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AnthraX1/AZ5/passsrc"
+)
+
+const defaultCheckpointInterval = 30 * time.Second
+
+// checkpointState is what -checkpoint persists: enough to fast-forward past
+// already-tried candidates on resume, plus (in -coordinator mode) the set
+// of chunks that have already been fully streamed.
+type checkpointState struct {
+	SourceURL  string `json:"source_url"`
+	SourceSize int64  `json:"source_size"`
+	SourceETag string `json:"source_etag,omitempty"`
+	// LastLine is a consumed high-water mark, not a produced one: every
+	// candidate derived from -passfile lines 1..LastLine has actually been
+	// tried by a worker, per lineTracker. A produced-but-unconsumed
+	// candidate sitting in passwordChan or the mangler's buffer at flush
+	// time is never counted here.
+	LastLine       uint64   `json:"last_line_number"`
+	TriedCount     uint64   `json:"tried_count"`
+	ElapsedSeconds float64  `json:"elapsed_seconds"`
+	FinishedShards []string `json:"finished_shards,omitempty"`
+}
+
+func loadCheckpoint(checkpointURL string) (*checkpointState, error) {
+	rc, err := passsrc.Open(checkpointURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var cp checkpointState
+	if err := json.NewDecoder(rc).Decode(&cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func saveCheckpoint(checkpointURL string, cp *checkpointState) error {
+	buf, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return passsrc.Write(checkpointURL, buf)
+}
+
+// resumeLine looks up an existing checkpoint for passfile and returns how
+// many lines to skip, or 0 to start from scratch. It refuses to resume if
+// the checkpoint was written for a different source, or if the source's
+// size/ETag has changed since then.
+func resumeLine(checkpointURL, passfile string) uint64 {
+	cp, err := loadCheckpoint(checkpointURL)
+	if err != nil {
+		return 0
+	}
+	if cp.SourceURL != passfile {
+		log.Println("checkpoint: -passfile does not match the checkpoint's source, starting over")
+		return 0
+	}
+
+	size, etag, err := passsrc.Stat(passfile)
+	if err != nil {
+		log.Println("checkpoint: unable to verify -passfile is unchanged, starting over:", err)
+		return 0
+	}
+	if size != cp.SourceSize || (cp.SourceETag != "" && etag != cp.SourceETag) {
+		log.Println("checkpoint: -passfile has changed size/ETag since the checkpoint was written, refusing to resume")
+		return 0
+	}
+
+	log.Println("checkpoint: resuming", passfile, "from line", cp.LastLine)
+	return cp.LastLine
+}
+
+// lineTracker computes -checkpoint's resume watermark: the highest
+// -passfile line number N such that every candidate derived from lines
+// 1..N has actually been tried by a worker (see worker's tried callback),
+// not merely produced into passwordChan or the mangler's buffer. Lines can
+// finish out of the order they were produced, since multiple workers pull
+// concurrently, so finished lines are held in doneLines until they form an
+// unbroken prefix starting at nextLine.
+type lineTracker struct {
+	mu                sync.Mutex
+	candidatesPerWord int
+	remaining         map[uint64]int
+	doneLines         map[uint64]bool
+	nextLine          uint64
+	triedTotal        atomic.Uint64
+}
+
+// newLineTracker sizes candidatesPerWord the same way startMangler does
+// (1 per word, or len(rules) when -rules expands each word), and starts
+// the watermark at startLine: lines up to and including startLine were
+// already accounted for by a prior checkpoint and are never re-produced.
+func newLineTracker(rulesPath string, startLine uint64) *lineTracker {
+	rules, err := loadRules(rulesPath)
+	if err != nil {
+		log.Fatal("unable to load -rules: ", err)
+	}
+	perWord := 1
+	if len(rules) > 0 {
+		perWord = len(rules)
+	}
+	return &lineTracker{
+		candidatesPerWord: perWord,
+		remaining:         make(map[uint64]int),
+		doneLines:         make(map[uint64]bool),
+		nextLine:          startLine + 1,
+	}
+}
+
+// onTried records that one candidate derived from line has been tried.
+// line 0 (-mask output, see candidate) has no -passfile position, so it
+// counts toward the stats total but never toward the resume watermark.
+func (t *lineTracker) onTried(line uint64) {
+	t.triedTotal.Add(1)
+	if line == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.remaining[line]
+	if !ok {
+		r = t.candidatesPerWord
+	}
+	r--
+	if r > 0 {
+		t.remaining[line] = r
+		return
+	}
+	delete(t.remaining, line)
+	t.doneLines[line] = true
+	for t.doneLines[t.nextLine] {
+		delete(t.doneLines, t.nextLine)
+		t.nextLine++
+	}
+}
+
+// watermark returns the highest line safe to skip on resume.
+func (t *lineTracker) watermark() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.nextLine - 1
+}
+
+// runCheckpointedProducer is passwordProducer plus periodic (and
+// SIGINT/SIGTERM-triggered) -checkpoint writes, and an initial fast-forward
+// past any line already accounted for by a prior run's checkpoint. It
+// returns the lineTracker driving those writes so the caller can wire its
+// onTried method into worker() as the per-candidate completion callback.
+func runCheckpointedProducer(config *Config, passwordChan chan candidate) *lineTracker {
+	skip := resumeLine(config.Checkpoint, config.Passfile)
+
+	var scanner *bufio.Scanner
+	var closer io.Closer
+	if config.Passfile == "-" {
+		scanner = bufio.NewScanner(os.Stdin)
+	} else {
+		rc, err := passsrc.Open(config.Passfile)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("Unable to open password source %s: %s", config.Passfile, err))
+		}
+		closer = rc
+		scanner = bufio.NewScanner(rc)
+	}
+
+	var lineNum uint64
+	for i := uint64(0); i < skip; i++ {
+		if !scanner.Scan() {
+			break
+		}
+		lineNum++
+	}
+
+	tracker := newLineTracker(config.Rules, lineNum)
+
+	start := time.Now()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go checkpointWriter(config, tracker, start, stop, done)
+
+	go func() {
+		defer close(passwordChan)
+		if closer != nil {
+			defer closer.Close()
+		}
+		for scanner.Scan() {
+			lineNum++
+			passwordChan <- candidate{word: scanner.Text(), line: lineNum}
+		}
+		close(stop)
+		<-done
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	return tracker
+}
+
+// checkpointWriter flushes config.Checkpoint every defaultCheckpointInterval
+// and once more on SIGINT/SIGTERM or when stop is closed, then signals done.
+func checkpointWriter(config *Config, tracker *lineTracker, start time.Time, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	flush := func() {
+		size, etag, _ := passsrc.Stat(config.Passfile)
+		cp := &checkpointState{
+			SourceURL:      config.Passfile,
+			SourceSize:     size,
+			SourceETag:     etag,
+			LastLine:       tracker.watermark(),
+			TriedCount:     tracker.triedTotal.Load(),
+			ElapsedSeconds: time.Since(start).Seconds(),
+		}
+		if err := saveCheckpoint(config.Checkpoint, cp); err != nil {
+			log.Println("checkpoint: write failed:", err)
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ticker := time.NewTicker(defaultCheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case sig := <-sigs:
+			log.Println("checkpoint: flushing on", sig, "before exit")
+			flush()
+			os.Exit(130)
+		case <-stop:
+			flush()
+			return
+		}
+	}
+}
+
+// runCoordinatorCheckpointer runs on the leader only (config.CoordinatorLeader)
+// when -checkpoint is set: it periodically records which chunks have been
+// fully streamed (redisDoneSet) as the checkpoint's high-water mark, so a
+// restarted fleet doesn't redo finished shards.
+func runCoordinatorCheckpointer(ctx context.Context, rdb *redis.Client, config *Config) {
+	ticker := time.NewTicker(defaultCheckpointInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		members, err := rdb.SMembers(ctx, redisDoneSet).Result()
+		if err != nil {
+			log.Println("checkpoint: SMEMBERS failed:", err)
+			continue
+		}
+		cp := &checkpointState{
+			SourceURL:      config.Passfile,
+			FinishedShards: members,
+		}
+		if err := saveCheckpoint(config.Checkpoint, cp); err != nil {
+			log.Println("checkpoint: write failed:", err)
+		}
+	}
+}
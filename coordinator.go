@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AnthraX1/AZ5/passsrc"
+)
+
+const (
+	redisPendingList    = "az5:chunks:pending"
+	redisProcessingList = "az5:chunks:processing"
+	redisClaimsHash     = "az5:chunks:claims"
+	redisDoneSet        = "az5:chunks:done"
+	redisCounterPrefix  = "az5:counter:"
+	redisFoundChannel   = "az5:found"
+	reaperInterval      = 30 * time.Second
+	defaultStaleAfter   = 5 * time.Minute
+)
+
+// chunk is the unit of work sharded by the coordinator leader: a slice of
+// a password source identified by byte offset and line count, so a worker
+// on any machine can seek straight to its slice without re-reading
+// everything before it.
+type chunk struct {
+	ID         string `json:"id"`
+	SourceURL  string `json:"source_url"`
+	ByteOffset int64  `json:"byte_offset"`
+	LineCount  int    `json:"line_count"`
+}
+
+// chunkID deterministically names a chunk from its source and starting
+// byte offset, so the same passfile sharded the same way always yields the
+// same IDs across restarts.
+func chunkID(sourceURL string, byteOffset int64) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d", sourceURL, byteOffset)))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRedisClient(redisURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -coordinator URL: %s", err)
+	}
+	return redis.NewClient(opts), nil
+}
+
+// runCoordinatorLeader reads passfile once, splitting it into chunkSize-line
+// chunks and pushing their descriptors onto the pending list, then starts
+// the reaper, which keeps running for the lifetime of the process so a
+// crashed worker's claimed chunk is eventually requeued.
+//
+// For range-capable backends (local files, S3) OpenRange seeks into the
+// *raw* bytes of the source (it does not re-decompress), so chunk offsets
+// must be raw byte offsets too; a
+// compressed passfile would make every offset but the first point into the
+// middle of the compressed stream. -coordinator therefore requires an
+// uncompressed source.
+func runCoordinatorLeader(ctx context.Context, rdb *redis.Client, config *Config) {
+	passfile, chunkSize := config.Passfile, config.ChunkSize
+
+	if passsrc.Compressed(passfile) {
+		log.Fatal("coordinator: -passfile appears compressed; -coordinator shards on raw byte offsets and requires an uncompressed source")
+	}
+
+	alreadyDone := make(map[string]bool)
+	if config.Checkpoint != "" {
+		if cp, err := loadCheckpoint(config.Checkpoint); err == nil && cp.SourceURL == passfile {
+			for _, id := range cp.FinishedShards {
+				alreadyDone[id] = true
+			}
+			if len(alreadyDone) > 0 {
+				log.Println("coordinator: resuming,", len(alreadyDone), "shards already finished")
+			}
+		}
+	}
+
+	rc, err := passsrc.Open(passfile)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("coordinator: unable to open %s: %s", passfile, err))
+	}
+	defer rc.Close()
+
+	reader := bufio.NewReader(rc)
+
+	var offset, chunkStart int64
+	lines := 0
+	skipped := 0
+	pushChunk := func() {
+		if lines == 0 {
+			return
+		}
+		// The ID is derived from the source and starting byte offset, not
+		// random, so re-sharding the same passfile with the same
+		// -chunk-size after a restart reproduces the same IDs and a
+		// checkpoint's FinishedShards can actually be matched against them.
+		c := chunk{ID: chunkID(passfile, chunkStart), SourceURL: passfile, ByteOffset: chunkStart, LineCount: lines}
+		chunkStart = offset
+		lines = 0
+
+		if alreadyDone[c.ID] {
+			skipped++
+			return
+		}
+		buf, _ := json.Marshal(c)
+		if err := rdb.LPush(ctx, redisPendingList, buf).Err(); err != nil {
+			log.Fatal("coordinator: failed to push chunk: ", err)
+		}
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			offset += int64(len(line))
+			lines++
+			if lines >= chunkSize {
+				pushChunk()
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			log.Fatal(err)
+		}
+	}
+	pushChunk()
+
+	log.Println("coordinator: finished sharding", passfile, "(skipped", skipped, "already-finished shards)")
+	go reaperLoop(ctx, rdb, defaultStaleAfter)
+}
+
+// reaperLoop moves chunks that have sat in the processing list for longer
+// than staleAfter (their worker presumably crashed) back onto pending.
+func reaperLoop(ctx context.Context, rdb *redis.Client, staleAfter time.Duration) {
+	for {
+		time.Sleep(reaperInterval)
+
+		entries, err := rdb.LRange(ctx, redisProcessingList, 0, -1).Result()
+		if err != nil {
+			log.Println("coordinator: reaper LRANGE failed:", err)
+			continue
+		}
+
+		for _, entry := range entries {
+			var c chunk
+			if err := json.Unmarshal([]byte(entry), &c); err != nil {
+				continue
+			}
+			claimedAtStr, err := rdb.HGet(ctx, redisClaimsHash, c.ID).Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				continue
+			}
+			claimedAt, err := strconv.ParseInt(claimedAtStr, 10, 64)
+			if err != nil {
+				continue
+			}
+			if time.Since(time.Unix(claimedAt, 0)) < staleAfter {
+				continue
+			}
+
+			log.Println("coordinator: reaping stale chunk", c.ID)
+			rdb.LRem(ctx, redisProcessingList, 1, entry)
+			rdb.HDel(ctx, redisClaimsHash, c.ID)
+			rdb.LPush(ctx, redisPendingList, entry)
+		}
+	}
+}
+
+// brpoplpushPollInterval bounds how long runCoordinatorProducer blocks on an
+// empty pending list before checking whether the whole run has drained.
+const brpoplpushPollInterval = 5 * time.Second
+
+// runCoordinatorProducer replaces passwordProducer in -coordinator mode: it
+// claims chunks from Redis instead of reading the whole wordlist locally,
+// seeking each source to its recorded byte offset before streaming its
+// LineCount lines into passwordChan.
+func runCoordinatorProducer(ctx context.Context, rdb *redis.Client, passwordChan chan candidate) {
+	defer close(passwordChan)
+
+	for {
+		result, err := rdb.BRPopLPush(ctx, redisPendingList, redisProcessingList, brpoplpushPollInterval).Result()
+		if err == redis.Nil {
+			// Pending was empty for a whole poll interval. Only stop if
+			// processing is empty too - otherwise a peer still holds a
+			// chunk (or is mid-reap) and more work may reappear.
+			pending, _ := rdb.LLen(ctx, redisPendingList).Result()
+			processing, _ := rdb.LLen(ctx, redisProcessingList).Result()
+			if pending == 0 && processing == 0 {
+				log.Println("coordinator: queue drained")
+				return
+			}
+			continue
+		}
+		if err != nil {
+			log.Fatal("coordinator: BRPOPLPUSH failed: ", err)
+		}
+
+		var c chunk
+		if err := json.Unmarshal([]byte(result), &c); err != nil {
+			log.Println("coordinator: dropping malformed chunk:", err)
+			rdb.LRem(ctx, redisProcessingList, 1, result)
+			continue
+		}
+
+		rdb.HSet(ctx, redisClaimsHash, c.ID, time.Now().Unix())
+
+		if err := streamChunk(c, passwordChan); err != nil {
+			// Unlike a crashed worker (recovered by the reaper off the
+			// processing list), a stream error is detected by the worker
+			// still holding the claim, so re-push it onto pending directly
+			// rather than leaving it for the reaper to notice later.
+			log.Println("coordinator: chunk", c.ID, "failed, re-queuing:", err)
+			rdb.LPush(ctx, redisPendingList, result)
+		} else {
+			rdb.SAdd(ctx, redisDoneSet, c.ID)
+		}
+
+		rdb.LRem(ctx, redisProcessingList, 1, result)
+		rdb.HDel(ctx, redisClaimsHash, c.ID)
+	}
+}
+
+func streamChunk(c chunk, passwordChan chan candidate) error {
+	rc, err := passsrc.OpenRange(c.SourceURL, c.ByteOffset)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	scanner := bufio.NewScanner(rc)
+	for i := 0; i < c.LineCount && scanner.Scan(); i++ {
+		// line 0: -coordinator checkpoints progress via redisDoneSet
+		// (whole chunks), not the per-line lineTracker watermark.
+		passwordChan <- candidate{word: scanner.Text()}
+	}
+	return scanner.Err()
+}
+
+// incrCoordinatorCounter replaces the local atomic counter in -coordinator
+// mode: every worker owns its own Redis key so coordinatorStat can SCAN and sum
+// them for a fleet-wide rate instead of a per-process one.
+func incrCoordinatorCounter(ctx context.Context, rdb *redis.Client, workerID string, delta int64) {
+	rdb.IncrBy(ctx, redisCounterPrefix+workerID, delta)
+}
+
+// coordinatorStat sums every az5:counter:* key across the fleet, replacing
+// genStat's local atomic.LoadUint64 in -coordinator mode.
+func coordinatorStat(ctx context.Context, rdb *redis.Client) {
+	start := time.Now()
+	for {
+		time.Sleep(2 * time.Second)
+
+		var total int64
+		iter := rdb.Scan(ctx, 0, redisCounterPrefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			v, err := rdb.Get(ctx, iter.Val()).Int64()
+			if err == nil {
+				total += v
+			}
+		}
+
+		exp := time.Since(start)
+		fmt.Print("\033[u\033[K")
+		fmt.Printf("\rTime passed: %s Fleet speed %f per second", exp.String(), float64(total)/exp.Seconds())
+	}
+}
+
+// announceFound publishes on the found channel so every peer worker can
+// exit cleanly instead of grinding through the rest of their chunk.
+func announceFound(ctx context.Context, rdb *redis.Client, username, password string) {
+	rdb.Publish(ctx, redisFoundChannel, fmt.Sprintf("%s:%s", username, password))
+}
+
+// watchForFound blocks until another peer finds the password and then exits
+// the process. If the subscription errors out it returns without exiting.
+func watchForFound(ctx context.Context, rdb *redis.Client) {
+	sub := rdb.Subscribe(ctx, redisFoundChannel)
+	defer sub.Close()
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		return
+	}
+	log.Println("Found password on another worker:", msg.Payload)
+	os.Exit(0)
+}
@@ -0,0 +1,76 @@
+package main
+
+import "fmt"
+
+var maskCharsets = map[byte]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// maskToken is one position in a parsed mask: either a literal byte or a
+// "?x" charset reference.
+type maskToken struct {
+	literal bool
+	char    byte
+	charset string
+}
+
+// parseMask turns a hashcat-style mask ("?l?l?l?d?d?d?s") into its
+// per-position tokens. "??" escapes a literal question mark.
+func parseMask(mask string) ([]maskToken, error) {
+	var tokens []maskToken
+	for i := 0; i < len(mask); i++ {
+		if mask[i] != '?' {
+			tokens = append(tokens, maskToken{literal: true, char: mask[i]})
+			continue
+		}
+		if i+1 >= len(mask) {
+			return nil, fmt.Errorf("mask ends with a dangling '?'")
+		}
+		class := mask[i+1]
+		i++
+		if class == '?' {
+			tokens = append(tokens, maskToken{literal: true, char: '?'})
+			continue
+		}
+		charset, ok := maskCharsets[class]
+		if !ok {
+			return nil, fmt.Errorf("unknown mask class '?%c'", class)
+		}
+		tokens = append(tokens, maskToken{charset: charset})
+	}
+	return tokens, nil
+}
+
+// generateMask walks the full keyspace described by mask, in order, writing
+// every candidate to out tagged with line 0, since mask output has no
+// -passfile position and never counts toward -checkpoint's resume watermark.
+func generateMask(mask string, out chan<- candidate) error {
+	tokens, err := parseMask(mask)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, len(tokens))
+	var recurse func(pos int)
+	recurse = func(pos int) {
+		if pos == len(tokens) {
+			out <- candidate{word: string(buf)}
+			return
+		}
+		t := tokens[pos]
+		if t.literal {
+			buf[pos] = t.char
+			recurse(pos + 1)
+			return
+		}
+		for i := 0; i < len(t.charset); i++ {
+			buf[pos] = t.charset[i]
+			recurse(pos + 1)
+		}
+	}
+	recurse(0)
+	return nil
+}
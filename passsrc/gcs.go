@@ -0,0 +1,50 @@
+package passsrc
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+func init() {
+	Register("gs", gcsBackend{})
+}
+
+type gcsBackend struct{}
+
+// Open reads "gs://bucket/object" using Application Default Credentials
+// (GOOGLE_APPLICATION_CREDENTIALS, GCE/GKE metadata, etc.) the same way
+// every other gcloud-aware tool resolves them.
+func (gcsBackend) Open(rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	bucket := u.Host
+	object := strings.TrimPrefix(u.Path, "/")
+
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	rc, err := decompress(object, r.Attrs.ContentEncoding, r)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return readCloser{rc, closerFunc(func() error {
+		rc.Close()
+		return client.Close()
+	})}, nil
+}
@@ -0,0 +1,73 @@
+package passsrc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+)
+
+func init() {
+	Register("http", httpBackend{})
+	Register("https", httpBackend{})
+}
+
+type httpBackend struct{}
+
+func (httpBackend) Open(rawURL string) (io.ReadCloser, error) {
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("passsrc: GET %s: unexpected status %s", rawURL, resp.Status)
+	}
+
+	body := io.ReadCloser(&resumingBody{url: rawURL, resp: resp})
+	return decompress(path.Base(rawURL), resp.Header.Get("Content-Encoding"), body)
+}
+
+// resumingBody wraps the response body of an http(s) password source and
+// transparently resumes with a Range request if the connection drops
+// mid-stream, rather than handing the worker pool a truncated wordlist.
+type resumingBody struct {
+	url    string
+	resp   *http.Response
+	offset int64
+}
+
+func (b *resumingBody) Read(p []byte) (int, error) {
+	n, err := b.resp.Body.Read(p)
+	b.offset += int64(n)
+	if err != nil && err != io.EOF {
+		if resumed, rerr := b.resume(); rerr == nil {
+			b.resp.Body.Close()
+			b.resp = resumed
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+func (b *resumingBody) resume() (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("passsrc: resume GET %s: server does not support range requests (status %s)", b.url, resp.Status)
+	}
+	return resp, nil
+}
+
+func (b *resumingBody) Close() error {
+	return b.resp.Body.Close()
+}
@@ -0,0 +1,56 @@
+package passsrc
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+func init() {
+	Register("vault", vaultBackend{})
+}
+
+type vaultBackend struct{}
+
+// Open reads "vault://path/to/secret#field" (field defaults to "wordlist")
+// and treats that field's value as the entire contents of a password list,
+// one candidate per line. Authentication follows the Vault client's default
+// configuration: VAULT_ADDR/VAULT_TOKEN from the environment. No AppRole (or
+// other auth method) login is performed.
+func (vaultBackend) Open(rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	if u.Host != "" {
+		path = u.Host + "/" + path
+	}
+	field := u.Fragment
+	if field == "" {
+		field = "wordlist"
+	}
+
+	client, err := vaultapi.NewClient(vaultapi.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := client.Logical().Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("passsrc: no secret found at vault path %q", path)
+	}
+
+	value, ok := secret.Data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("passsrc: vault secret %q has no string field %q", path, field)
+	}
+
+	return io.NopCloser(strings.NewReader(value)), nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ruleOp is one hashcat-style rule function: an opcode byte plus whatever
+// arguments it takes (0-2 bytes, depending on the opcode).
+type ruleOp struct {
+	op   byte
+	args []byte
+}
+
+// loadRules reads a hashcat-style rule file, one rule (a space-separated
+// sequence of ruleOps) per non-empty, non-comment line. An empty path
+// returns a nil rule set, which the caller treats as a passthrough.
+func loadRules(path string) ([][]ruleOp, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules [][]ruleOp
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %s", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// parseRule splits a rule line into its ruleOps. Functions are packed
+// tightly (no separator) except where an explicit space is used to combine
+// independent functions on one line, e.g. "c $1 $2 $3".
+func parseRule(line string) ([]ruleOp, error) {
+	var ops []ruleOp
+	for _, field := range strings.Fields(line) {
+		i := 0
+		for i < len(field) {
+			op := field[i]
+			argc, err := argCount(op)
+			if err != nil {
+				return nil, err
+			}
+			if i+1+argc > len(field) {
+				return nil, fmt.Errorf("function %q missing arguments", string(op))
+			}
+			ops = append(ops, ruleOp{op: op, args: []byte(field[i+1 : i+1+argc])})
+			i += 1 + argc
+		}
+	}
+	return ops, nil
+}
+
+func argCount(op byte) (int, error) {
+	switch op {
+	case ':', 'l', 'u', 'c', 'C', 'd', 'r', 't':
+		return 0, nil
+	case '$', '^', 'T':
+		return 1, nil
+	case 's', 'o':
+		return 2, nil
+	case 'i':
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("unsupported rule function %q", string(op))
+	}
+}
+
+// applyRule runs one parsed rule against word, returning the mangled
+// candidate. Position arguments ('i', 'o', 'T') follow hashcat's
+// convention of a single base-94 printable-ASCII digit (here: a plain
+// decimal digit 0-9 is enough for the wordlists this tool targets).
+func applyRule(rule []ruleOp, word string) string {
+	w := []byte(word)
+	for _, op := range rule {
+		switch op.op {
+		case ':':
+			// nop
+		case 'l':
+			w = []byte(strings.ToLower(string(w)))
+		case 'u':
+			w = []byte(strings.ToUpper(string(w)))
+		case 'c':
+			w = capitalize(w)
+		case 'C':
+			w = invertCapitalize(w)
+		case 'd':
+			w = append(append([]byte{}, w...), w...)
+		case 'r':
+			w = reverseBytes(w)
+		case 't':
+			w = toggleAll(w)
+		case '$':
+			w = append(w, op.args[0])
+		case '^':
+			w = append([]byte{op.args[0]}, w...)
+		case 's':
+			w = substitute(w, op.args[0], op.args[1])
+		case 'i':
+			w = insertAt(w, position(op.args[0]), op.args[1])
+		case 'o':
+			w = overwriteAt(w, position(op.args[0]), op.args[1])
+		case 'T':
+			w = toggleAt(w, position(op.args[0]))
+		}
+	}
+	return string(w)
+}
+
+func position(digit byte) int {
+	n, err := strconv.Atoi(string(digit))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func capitalize(w []byte) []byte {
+	if len(w) == 0 {
+		return w
+	}
+	out := []byte(strings.ToLower(string(w)))
+	out[0] = toUpperByte(out[0])
+	return out
+}
+
+func invertCapitalize(w []byte) []byte {
+	if len(w) == 0 {
+		return w
+	}
+	out := []byte(strings.ToUpper(string(w)))
+	out[0] = toLowerByte(out[0])
+	return out
+}
+
+func reverseBytes(w []byte) []byte {
+	out := make([]byte, len(w))
+	for i, b := range w {
+		out[len(w)-1-i] = b
+	}
+	return out
+}
+
+func substitute(w []byte, from, to byte) []byte {
+	out := make([]byte, len(w))
+	for i, b := range w {
+		if b == from {
+			out[i] = to
+		} else {
+			out[i] = b
+		}
+	}
+	return out
+}
+
+func insertAt(w []byte, pos int, c byte) []byte {
+	if pos < 0 || pos > len(w) {
+		return w
+	}
+	out := make([]byte, 0, len(w)+1)
+	out = append(out, w[:pos]...)
+	out = append(out, c)
+	out = append(out, w[pos:]...)
+	return out
+}
+
+func overwriteAt(w []byte, pos int, c byte) []byte {
+	if pos < 0 || pos >= len(w) {
+		return w
+	}
+	out := append([]byte{}, w...)
+	out[pos] = c
+	return out
+}
+
+// toggleAll implements hashcat's 't' rule: toggle the case of every
+// alphabetic byte in the word, in one pass.
+func toggleAll(w []byte) []byte {
+	out := append([]byte{}, w...)
+	for i := range out {
+		out[i] = toggleCaseByte(out[i])
+	}
+	return out
+}
+
+func toggleCaseByte(b byte) byte {
+	switch {
+	case b >= 'a' && b <= 'z':
+		return toUpperByte(b)
+	case b >= 'A' && b <= 'Z':
+		return toLowerByte(b)
+	default:
+		return b
+	}
+}
+
+func toggleAt(w []byte, pos int) []byte {
+	if pos < 0 || pos >= len(w) {
+		return w
+	}
+	out := append([]byte{}, w...)
+	out[pos] = toggleCaseByte(out[pos])
+	return out
+}
+
+func toUpperByte(b byte) byte {
+	if b >= 'a' && b <= 'z' {
+		return b - ('a' - 'A')
+	}
+	return b
+}
+
+func toLowerByte(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + ('a' - 'A')
+	}
+	return b
+}
+
+// candidate is one password guess flowing from a producer through the
+// mangler to the worker pool. line is the 1-based -passfile line it derives
+// from, or 0 for -mask output (which has no -passfile position and is
+// therefore never counted toward -checkpoint's resume watermark).
+type candidate struct {
+	word string
+	line uint64
+}
+
+// startMangler sits between passwordProducer and the worker pool. When
+// neither -rules nor -mask is set it degenerates to a passthrough of
+// passwordChan so the common case pays no extra cost. Otherwise it owns a
+// single writer goroutine per active source (dictionary+rules, and/or
+// mask), feeding a bounded mangledChan so the mask/rule expansion can never
+// outrun the worker pool's PBKDF2 throughput.
+func startMangler(config *Config, passwordChan chan candidate) chan candidate {
+	if config.Rules == "" && config.Mask == "" {
+		return passwordChan
+	}
+
+	rules, err := loadRules(config.Rules)
+	if err != nil {
+		log.Fatal("unable to load -rules: ", err)
+	}
+
+	mangledChan := make(chan candidate, 1000)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for c := range passwordChan {
+			if len(rules) == 0 {
+				mangledChan <- c
+				continue
+			}
+			for _, rule := range rules {
+				mangledChan <- candidate{word: applyRule(rule, c.word), line: c.line}
+			}
+		}
+	}()
+
+	if config.Mask != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := generateMask(config.Mask, mangledChan); err != nil {
+				log.Fatal("unable to expand -mask: ", err)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(mangledChan)
+	}()
+
+	return mangledChan
+}